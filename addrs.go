@@ -0,0 +1,76 @@
+package websocket
+
+import (
+	"errors"
+	"net"
+	"net/url"
+
+	ma "github.com/multiformats/go-multiaddr"
+	mafmt "github.com/multiformats/go-multiaddr-fmt"
+)
+
+// P_WS is the multiaddr protocol code for plaintext WebSockets.
+// P_WSS is the multiaddr protocol code for WebSockets over TLS.
+const (
+	P_WS  = 477
+	P_WSS = 478
+)
+
+// Protocol is the multiaddr protocol definition for this transport.
+var Protocol = ma.Protocol{
+	Code:  P_WS,
+	Name:  "ws",
+	VCode: ma.CodeToVarint(P_WS),
+}
+
+// WssProtocol is the multiaddr protocol definition for the secure variant of
+// this transport.
+var WssProtocol = ma.Protocol{
+	Code:  P_WSS,
+	Name:  "wss",
+	VCode: ma.CodeToVarint(P_WSS),
+}
+
+// WsFmt matches any multiaddr ending in /ws, over TCP or DNS.
+var WsFmt = mafmt.And(mafmt.Or(mafmt.TCP, mafmt.DNS), mafmt.Base(P_WS))
+
+// WssFmt matches any multiaddr ending in /wss, over TCP or DNS.
+var WssFmt = mafmt.And(mafmt.Or(mafmt.TCP, mafmt.DNS), mafmt.Base(P_WSS))
+
+func init() {
+	// The "ws" protocol is already registered by go-multiaddr itself at the
+	// same code; Protocol exists here only so WsFmt/WithTLSConfig-adjacent
+	// code has a ma.Protocol value to refer to. Only WssProtocol is actually
+	// new and needs registering.
+	if err := ma.AddProtocol(WssProtocol); err != nil {
+		panic(errors.New("could not add wss protocol: " + err.Error()))
+	}
+}
+
+// Addr is an implementation of net.Addr for WebSocket addresses.
+type Addr struct {
+	*url.URL
+}
+
+var _ net.Addr = (*Addr)(nil)
+
+// NewAddr creates a new WebSocket net.Addr for the given host, e.g.
+// "1.2.3.4:1234". secure selects whether the Addr reports the "wss" or
+// "ws" scheme.
+func NewAddr(host string, secure bool) *Addr {
+	scheme := "ws"
+	if secure {
+		scheme = "wss"
+	}
+	return &Addr{
+		URL: &url.URL{
+			Scheme: scheme,
+			Host:   host,
+		},
+	}
+}
+
+// Network returns the network for a WebSocket, "websocket".
+func (addr *Addr) Network() string {
+	return "websocket"
+}
@@ -42,12 +42,13 @@ type Conn struct {
 
 // NewConn creates a Conn given a regular js/wasm WebSocket Conn.
 func NewConn(raw js.Value) *Conn {
+	secure := strings.HasPrefix(raw.Get("url").String(), "wss://")
 	conn := &Conn{
 		Value:       raw,
 		closeSignal: make(chan struct{}),
 		dataSignal:  make(chan struct{}, 1),
-		localAddr:   NewAddr("0.0.0.0:0"),
-		remoteAddr:  getRemoteAddr(raw),
+		localAddr:   NewAddr("0.0.0.0:0", secure),
+		remoteAddr:  getRemoteAddr(raw, secure),
 	}
 	// Force the JavaScript WebSockets API to use the ArrayBuffer type for
 	// incoming messages instead of the Blob type. This is better for us because
@@ -132,6 +133,17 @@ func (c *Conn) Close() error {
 	return nil
 }
 
+// CloseWithError closes the connection like Close, but sends code (folded
+// into the RFC 6455 application-specific range 4000-4999 via
+// closeCodeErrorBase+code%1000) as the WebSocket close code and msg as the
+// close reason.
+func (c *Conn) CloseWithError(code uint64, msg string) error {
+	c.signalClose()
+	c.Call("close", closeCodeErrorBase+int(code%1000), msg)
+	c.releaseHandlers()
+	return nil
+}
+
 func (c *Conn) signalClose() {
 	c.closeOnce.Do(func() {
 		close(c.closeSignal)
@@ -159,11 +171,12 @@ func (c *Conn) LocalAddr() net.Addr {
 	return c.localAddr
 }
 
-func getRemoteAddr(val js.Value) net.Addr {
+func getRemoteAddr(val js.Value, secure bool) net.Addr {
 	rawURL := val.Get("url").String()
-	withoutPrefix := strings.TrimPrefix(rawURL, "ws://")
+	withoutPrefix := strings.TrimPrefix(rawURL, "wss://")
+	withoutPrefix = strings.TrimPrefix(withoutPrefix, "ws://")
 	withoutSuffix := strings.TrimSuffix(withoutPrefix, "/")
-	return NewAddr(withoutSuffix)
+	return NewAddr(withoutSuffix, secure)
 }
 
 func (c *Conn) RemoteAddr() net.Addr {
@@ -184,6 +197,14 @@ func (c *Conn) SetWriteDeadline(t time.Time) error {
 	return nil
 }
 
+// KeepAlive is a no-op in js/wasm: the browser's WebSocket implementation
+// handles ping/pong keep-alive itself.
+func (c *Conn) KeepAlive(interval, timeout time.Duration) {}
+
+// EnableCompression is a no-op in js/wasm: browsers negotiate
+// permessage-deflate automatically and don't expose per-write control.
+func (c *Conn) EnableCompression(level, threshold int) {}
+
 func (c *Conn) setUpHandlers() {
 	c.mut.Lock()
 	defer c.mut.Unlock()
@@ -267,6 +288,12 @@ func arrayBufferToBytes(buffer js.Value) []byte {
 }
 
 func errorEventToError(val js.Value) error {
+	if gotCode := val.Get("code"); gotCode != js.Undefined() {
+		if code := gotCode.Int(); isReservedCloseCode(code) {
+			return &ConnError{Code: uint64(code - closeCodeErrorBase)}
+		}
+	}
+
 	var typ string
 	if gotType := val.Get("type"); gotType != js.Undefined() {
 		typ = gotType.String()
@@ -12,13 +12,34 @@ import (
 	"go.uber.org/multierr"
 )
 
+// GracefulCloseTimeout is the time allowed to write a close control frame
+// before giving up and closing the underlying TCP connection anyway.
+const GracefulCloseTimeout = 100 * time.Millisecond
+
 // Conn implements net.Conn interface for gorilla/websocket.
 type Conn struct {
 	*ws.Conn
 	DefaultMessageType int
 	reader             io.Reader
 	closeOnce          sync.Once
+	closed             chan struct{}
 	mux                sync.RWMutex
+
+	// recycler and dest are set when the transport has connection reuse
+	// enabled. When non-nil, Close hands the raw connection back to the
+	// pool instead of tearing it down.
+	recycler ConnectionManager
+	dest     string
+
+	// compressionThreshold, when non-zero, disables write compression for
+	// messages smaller than this many bytes even though compression was
+	// enabled via EnableCompression.
+	compressionThreshold int
+
+	// secure records whether this Conn was dialed/accepted over wss, so
+	// LocalAddr/RemoteAddr can report the right scheme. Set by the
+	// transport/listener at construction time.
+	secure bool
 }
 
 func (c *Conn) Read(b []byte) (int, error) {
@@ -58,8 +79,11 @@ func (c *Conn) prepNextReader() error {
 	t, r, err := c.Conn.NextReader()
 	if err != nil {
 		if wserr, ok := err.(*ws.CloseError); ok {
-			if wserr.Code == 1000 || wserr.Code == 1005 {
+			switch {
+			case wserr.Code == 1000 || wserr.Code == 1005:
 				return io.EOF
+			case isReservedCloseCode(wserr.Code):
+				return &ConnError{Code: uint64(wserr.Code - closeCodeErrorBase)}
 			}
 		}
 		return err
@@ -73,8 +97,22 @@ func (c *Conn) prepNextReader() error {
 	return nil
 }
 
+// EnableCompression turns on RFC 7692 permessage-deflate for outbound
+// writes at the given flate level (see compress/flate), skipping
+// compression for individual writes smaller than threshold bytes.
+func (c *Conn) EnableCompression(level, threshold int) {
+	c.mux.Lock()
+	c.Conn.EnableWriteCompression(true)
+	c.Conn.SetCompressionLevel(level)
+	c.compressionThreshold = threshold
+	c.mux.Unlock()
+}
+
 func (c *Conn) Write(b []byte) (n int, err error) {
 	c.mux.Lock()
+	if c.compressionThreshold > 0 {
+		c.Conn.EnableWriteCompression(len(b) >= c.compressionThreshold)
+	}
 	if err := c.Conn.WriteMessage(c.DefaultMessageType, b); err != nil {
 		c.mux.Unlock()
 		return 0, err
@@ -86,12 +124,40 @@ func (c *Conn) Write(b []byte) (n int, err error) {
 // Close closes the connection. Only the first call to Close will receive the
 // close error, subsequent and concurrent calls will return nil.
 // This method is thread-safe.
+//
+// If the connection was handed out by a ConnectionManager, Close instead
+// recycles the raw websocket connection back into the pool; the wire is only
+// torn down once the pool evicts or closes it.
 func (c *Conn) Close() error {
 	var err error
 	c.closeOnce.Do(func() {
+		close(c.closed)
+
+		c.mux.Lock()
+		recycler, dest := c.recycler, c.dest
+		c.mux.Unlock()
+
+		if recycler != nil {
+			recycler.Recycle(dest, c)
+			return
+		}
+		err = c.reallyClose()
+	})
+	return err
+}
+
+// CloseWithError closes the connection like Close, but sends code (folded
+// into the RFC 6455 application-specific range 4000-4999 via
+// closeCodeErrorBase+code%1000) as the WebSocket close code and msg as the
+// close reason, instead of a normal closure. It bypasses connection reuse:
+// the underlying websocket is always torn down, never recycled.
+func (c *Conn) CloseWithError(code uint64, msg string) error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.closed)
 		err1 := c.Conn.WriteControl(
 			ws.CloseMessage,
-			ws.FormatCloseMessage(ws.CloseNormalClosure, "closed"),
+			ws.FormatCloseMessage(closeCodeErrorBase+int(code%1000), msg),
 			time.Now().Add(GracefulCloseTimeout),
 		)
 		err2 := c.Conn.Close()
@@ -100,12 +166,25 @@ func (c *Conn) Close() error {
 	return err
 }
 
+// reallyClose sends a close control frame and tears down the underlying
+// websocket connection. It bypasses closeOnce so a ConnectionManager can
+// call it once an idle Conn is evicted from the pool.
+func (c *Conn) reallyClose() error {
+	err1 := c.Conn.WriteControl(
+		ws.CloseMessage,
+		ws.FormatCloseMessage(ws.CloseNormalClosure, "closed"),
+		time.Now().Add(GracefulCloseTimeout),
+	)
+	err2 := c.Conn.Close()
+	return multierr.Combine(err1, err2)
+}
+
 func (c *Conn) LocalAddr() net.Addr {
-	return NewAddr(c.Conn.LocalAddr().String())
+	return NewAddr(c.Conn.LocalAddr().String(), c.secure)
 }
 
 func (c *Conn) RemoteAddr() net.Addr {
-	return NewAddr(c.Conn.RemoteAddr().String())
+	return NewAddr(c.Conn.RemoteAddr().String(), c.secure)
 }
 
 func (c *Conn) SetDeadline(t time.Time) error {
@@ -127,10 +206,65 @@ func (c *Conn) SetWriteDeadline(t time.Time) error {
 	return err
 }
 
+// checkout resets closeOnce so a Conn taken back out of a connection pool
+// can be closed (and, if reuse is still enabled, recycled again) exactly
+// once more.
+func (c *Conn) checkout() {
+	c.closeOnce = sync.Once{}
+	c.closed = make(chan struct{})
+}
+
+// KeepAlive starts a background goroutine that pings the remote peer every
+// interval and pairs it with a read deadline of timeout, extended each time
+// a pong arrives. If no pong is seen within timeout, the next Read call
+// fails with a timeout error and the connection should be closed by the
+// caller. The pinger stops automatically when Close is called.
+//
+// KeepAlive must not be called again on a Conn until Close has stopped the
+// previous pinger.
+func (c *Conn) KeepAlive(interval, timeout time.Duration) {
+	_ = c.Conn.SetReadDeadline(time.Now().Add(timeout))
+	c.Conn.SetPongHandler(func(string) error {
+		return c.Conn.SetReadDeadline(time.Now().Add(timeout))
+	})
+
+	go c.pingLoop(interval)
+}
+
+func (c *Conn) pingLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			deadline := time.Now().Add(interval)
+			if err := c.Conn.WriteControl(ws.PingMessage, nil, deadline); err != nil {
+				c.killOnPingFailure()
+				return
+			}
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+// killOnPingFailure tears down the connection after a failed keep-alive
+// ping. It clears recycler first so that its own Close tears the connection
+// down instead of handing a dead connection back to the pool. A Close that
+// already ran before recycler is cleared is not affected.
+func (c *Conn) killOnPingFailure() {
+	c.mux.Lock()
+	c.recycler = nil
+	c.mux.Unlock()
+	c.Close()
+}
+
 // NewConn creates a Conn given a regular gorilla/websocket Conn.
 func NewConn(raw *ws.Conn) *Conn {
 	return &Conn{
 		Conn:               raw,
 		DefaultMessageType: ws.BinaryMessage,
+		closed:             make(chan struct{}),
 	}
 }
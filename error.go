@@ -0,0 +1,24 @@
+package websocket
+
+import "fmt"
+
+// closeCodeErrorBase is the start of the close-code range (4000-4999) that
+// RFC 6455 reserves for application-specific use. This transport uses it to
+// carry libp2p transport error codes across CloseWithError.
+const closeCodeErrorBase = 4000
+
+// ConnError is returned from Conn.Read when the peer closed the connection
+// with an application error code sent via CloseWithError.
+type ConnError struct {
+	Code uint64
+}
+
+func (e *ConnError) Error() string {
+	return fmt.Sprintf("websocket connection closed with error code %d", e.Code)
+}
+
+// isReservedCloseCode reports whether code falls in the 4000-4999
+// application-specific range used by CloseWithError/ConnError.
+func isReservedCloseCode(code int) bool {
+	return code >= closeCodeErrorBase && code < closeCodeErrorBase+1000
+}
@@ -0,0 +1,162 @@
+// +build !js
+
+package websocket
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr-net"
+
+	ws "github.com/gorilla/websocket"
+)
+
+// listener implements manet.Listener for both plaintext ws and TLS-terminated
+// wss addresses. Incoming HTTP requests are upgraded to WebSocket
+// connections and handed off through incoming.
+type listener struct {
+	nl        net.Listener
+	laddr     ma.Multiaddr
+	secure    bool
+	server    http.Server
+	incoming  chan *Conn
+	closed    chan struct{}
+	closeOnce sync.Once
+	upgrader  ws.Upgrader
+
+	// keepAliveInterval and keepAliveTimeout, when keepAliveInterval is
+	// non-zero, are applied to every accepted Conn. Set from listenerConfig
+	// before the accept loop starts.
+	keepAliveInterval time.Duration
+	keepAliveTimeout  time.Duration
+
+	// compressionEnabled, compressionLevel and compressionThreshold, when
+	// compressionEnabled is true, are applied to every accepted Conn. Set
+	// from listenerConfig before the accept loop starts.
+	compressionEnabled   bool
+	compressionLevel     int
+	compressionThreshold int
+}
+
+// listenerConfig carries the WebsocketTransport settings a listener needs in
+// order to serve its very first accepted connection correctly. It is applied
+// to the listener before the accept loop starts, so there is no window in
+// which a connection could be accepted with default (i.e. disabled)
+// keep-alive or compression settings.
+type listenerConfig struct {
+	tlsConf *tls.Config
+
+	keepAliveInterval time.Duration
+	keepAliveTimeout  time.Duration
+
+	compressionEnabled   bool
+	compressionLevel     int
+	compressionThreshold int
+}
+
+// newListener creates a listener for a, terminating TLS itself when
+// cfg.tlsConf is non-nil (i.e. when a ends in /wss). cfg is applied to the
+// listener before it starts accepting connections, so every accepted Conn
+// sees a consistent, fully-configured listener.
+func newListener(a ma.Multiaddr, cfg listenerConfig) (*listener, error) {
+	lnet, lnaddr, err := manet.DialArgs(a)
+	if err != nil {
+		return nil, err
+	}
+
+	nl, err := net.Listen(lnet, lnaddr)
+	if err != nil {
+		return nil, err
+	}
+
+	secure := cfg.tlsConf != nil
+	if secure {
+		nl = tls.NewListener(nl, cfg.tlsConf)
+	}
+
+	laddr, err := manet.FromNetAddr(nl.Addr())
+	if err != nil {
+		nl.Close()
+		return nil, err
+	}
+	wsComponent := ma.StringCast("/ws")
+	if secure {
+		wsComponent = ma.StringCast("/wss")
+	}
+
+	l := &listener{
+		nl:       nl,
+		laddr:    laddr.Encapsulate(wsComponent),
+		secure:   secure,
+		incoming: make(chan *Conn),
+		closed:   make(chan struct{}),
+		upgrader: ws.Upgrader{
+			CheckOrigin: func(r *http.Request) bool {
+				return true
+			},
+			EnableCompression: cfg.compressionEnabled,
+		},
+		keepAliveInterval:    cfg.keepAliveInterval,
+		keepAliveTimeout:     cfg.keepAliveTimeout,
+		compressionEnabled:   cfg.compressionEnabled,
+		compressionLevel:     cfg.compressionLevel,
+		compressionThreshold: cfg.compressionThreshold,
+	}
+	l.server = http.Server{Handler: l}
+	go l.server.Serve(l.nl)
+	return l, nil
+}
+
+func (l *listener) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	wsc, err := l.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	c := NewConn(wsc)
+	c.secure = l.secure
+	if l.keepAliveInterval > 0 {
+		c.KeepAlive(l.keepAliveInterval, l.keepAliveTimeout)
+	}
+	if l.compressionEnabled {
+		c.EnableCompression(l.compressionLevel, l.compressionThreshold)
+	}
+
+	select {
+	case l.incoming <- c:
+	case <-l.closed:
+		c.Close()
+	}
+}
+
+func (l *listener) Accept() (manet.Conn, error) {
+	select {
+	case c, ok := <-l.incoming:
+		if !ok {
+			return nil, fmt.Errorf("listener is closed")
+		}
+		return manet.WrapNetConn(c)
+	case <-l.closed:
+		return nil, fmt.Errorf("listener is closed")
+	}
+}
+
+func (l *listener) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.closed)
+	})
+	return l.nl.Close()
+}
+
+func (l *listener) Addr() net.Addr {
+	return l.nl.Addr()
+}
+
+func (l *listener) Multiaddr() ma.Multiaddr {
+	return l.laddr
+}
@@ -0,0 +1,103 @@
+package websocket
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr-net"
+)
+
+func init() {
+	// NetAddrNetworks is keyed by Addr.Network() ("websocket", shared by both
+	// ws and wss), while ProtocolName is keyed by the multiaddr protocol
+	// name and so needs one codec per protocol. ParseWebsocketNetAddr and
+	// ParseWebsocketMultiaddr both already branch on the ws/wss scheme
+	// themselves, so the same pair of functions serves both codecs.
+	manet.RegisterNetCodec(&manet.NetCodec{
+		NetAddrNetworks:  []string{"websocket"},
+		ProtocolName:     "ws",
+		ParseNetAddr:     ParseWebsocketNetAddr,
+		ConvertMultiaddr: ParseWebsocketMultiaddr,
+	})
+	manet.RegisterNetCodec(&manet.NetCodec{
+		NetAddrNetworks:  []string{"websocket"},
+		ProtocolName:     "wss",
+		ParseNetAddr:     ParseWebsocketNetAddr,
+		ConvertMultiaddr: ParseWebsocketMultiaddr,
+	})
+}
+
+// parseMultiaddr converts a /ws or /wss multiaddr into a URL suitable for
+// passing to a gorilla/websocket Dialer.
+func parseMultiaddr(maddr ma.Multiaddr) (*url.URL, error) {
+	parts := ma.Split(maddr)
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("invalid websocket multiaddr: %s", maddr)
+	}
+
+	last := parts[len(parts)-1]
+	proto := last.Protocols()[0]
+
+	var scheme string
+	switch proto.Code {
+	case P_WS:
+		scheme = "ws"
+	case P_WSS:
+		scheme = "wss"
+	default:
+		return nil, fmt.Errorf("not a websocket multiaddr: %s", maddr)
+	}
+
+	network, host, err := manet.DialArgs(ma.Join(parts[:len(parts)-1]...))
+	if err != nil {
+		return nil, err
+	}
+	if network != "tcp" && network != "tcp4" && network != "tcp6" {
+		return nil, fmt.Errorf("unsupported websocket network %q in %s", network, maddr)
+	}
+
+	return &url.URL{Scheme: scheme, Host: host}, nil
+}
+
+// ParseWebsocketNetAddr converts a net.Addr belonging to a websocket
+// listener into a /ws or /wss multiaddr.
+func ParseWebsocketNetAddr(a net.Addr) (ma.Multiaddr, error) {
+	wsa, ok := a.(*Addr)
+	if !ok {
+		return nil, fmt.Errorf("not a websocket address: %s", a)
+	}
+
+	host, portStr, err := net.SplitHostPort(wsa.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	tcpaddr, err := net.ResolveTCPAddr("tcp", net.JoinHostPort(host, portStr))
+	if err != nil {
+		return nil, err
+	}
+
+	tcpMa, err := manet.FromNetAddr(tcpaddr)
+	if err != nil {
+		return nil, err
+	}
+
+	comp := ma.StringCast("/ws")
+	if wsa.Scheme == "wss" {
+		comp = ma.StringCast("/wss")
+	}
+
+	return tcpMa.Encapsulate(comp), nil
+}
+
+// ParseWebsocketMultiaddr converts a /ws or /wss multiaddr into a net.Addr.
+func ParseWebsocketMultiaddr(maddr ma.Multiaddr) (net.Addr, error) {
+	wsurl, err := parseMultiaddr(maddr)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewAddr(wsurl.Host, wsurl.Scheme == "wss"), nil
+}
@@ -0,0 +1,100 @@
+// +build !js
+
+package websocket
+
+import (
+	"sync"
+	"time"
+)
+
+// ConnectionManager pools idle websocket connections so that repeated dials
+// to the same destination can reuse an existing connection instead of
+// paying for a fresh TCP+WS handshake. It is invoked by Conn.Close when the
+// Conn was handed out by a manager.
+type ConnectionManager interface {
+	// Recycle offers c back to the pool for dest. c must not be used again
+	// by the caller after this returns.
+	Recycle(dest string, c *Conn)
+
+	// Take returns a previously recycled Conn for dest, ready for reuse, or
+	// nil if none is available.
+	Take(dest string) *Conn
+}
+
+// connPool is the default ConnectionManager. Idle connections are kept per
+// destination up to maxIdle, and evicted after idleTimeout of inactivity.
+type connPool struct {
+	mu          sync.Mutex
+	maxIdle     int
+	idleTimeout time.Duration
+	idle        map[string][]*idleConn
+}
+
+type idleConn struct {
+	conn  *Conn
+	timer *time.Timer
+}
+
+// newConnPool creates a connPool holding at most maxIdle idle connections
+// per destination, each expiring after idleTimeout.
+func newConnPool(maxIdle int, idleTimeout time.Duration) *connPool {
+	return &connPool{
+		maxIdle:     maxIdle,
+		idleTimeout: idleTimeout,
+		idle:        make(map[string][]*idleConn),
+	}
+}
+
+func (p *connPool) Take(dest string) *Conn {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	conns := p.idle[dest]
+	if len(conns) == 0 {
+		return nil
+	}
+
+	ic := conns[len(conns)-1]
+	p.idle[dest] = conns[:len(conns)-1]
+	ic.timer.Stop()
+	ic.conn.checkout()
+	return ic.conn
+}
+
+func (p *connPool) Recycle(dest string, c *Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.idle[dest]) >= p.maxIdle {
+		go c.reallyClose()
+		return
+	}
+
+	ic := &idleConn{conn: c}
+	ic.timer = time.AfterFunc(p.idleTimeout, func() {
+		p.evict(dest, ic)
+	})
+	p.idle[dest] = append(p.idle[dest], ic)
+}
+
+// evict removes target from the pool and closes its underlying connection.
+// It is called by target's idle timer once idleTimeout has elapsed. If
+// target was already removed (e.g. a concurrent Take won the race), the
+// connection is left alone: it now belongs to whoever took it.
+func (p *connPool) evict(dest string, target *idleConn) {
+	p.mu.Lock()
+	found := false
+	conns := p.idle[dest]
+	for i, ic := range conns {
+		if ic == target {
+			p.idle[dest] = append(conns[:i], conns[i+1:]...)
+			found = true
+			break
+		}
+	}
+	p.mu.Unlock()
+
+	if found {
+		target.conn.reallyClose()
+	}
+}
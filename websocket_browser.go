@@ -0,0 +1,88 @@
+// +build js,wasm
+
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"syscall/js"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	tpt "github.com/libp2p/go-libp2p-core/transport"
+	tptu "github.com/libp2p/go-libp2p-transport-upgrader"
+
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr-net"
+)
+
+// WebsocketTransport is the actual go-libp2p transport for WebSockets in
+// js/wasm. It can only dial; browsers cannot accept incoming connections.
+type WebsocketTransport struct {
+	Upgrader *tptu.Upgrader
+}
+
+var _ tpt.Transport = (*WebsocketTransport)(nil)
+
+// Option configures a WebsocketTransport. In js/wasm the browser manages
+// TLS, proxying and compression itself. Of the native build's options, only
+// WithCompression is defined here, and it has no effect.
+type Option func(*WebsocketTransport)
+
+// WithCompression is a no-op in js/wasm: browsers negotiate
+// permessage-deflate automatically. It exists so that code passing
+// WithCompression to New compiles on every platform.
+func WithCompression(level, threshold int) Option {
+	return func(t *WebsocketTransport) {}
+}
+
+// New creates a new WebsocketTransport, applying any options given.
+func New(u *tptu.Upgrader, opts ...Option) *WebsocketTransport {
+	t := &WebsocketTransport{Upgrader: u}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+func (t *WebsocketTransport) CanDial(a ma.Multiaddr) bool {
+	return WsFmt.Matches(a) || WssFmt.Matches(a)
+}
+
+func (t *WebsocketTransport) Protocols() []int {
+	return []int{P_WS, P_WSS}
+}
+
+func (t *WebsocketTransport) Proxy() bool {
+	return false
+}
+
+func (t *WebsocketTransport) Resolve(ctx context.Context, maddr ma.Multiaddr) ([]ma.Multiaddr, error) {
+	return []ma.Multiaddr{maddr}, nil
+}
+
+func (t *WebsocketTransport) Dial(ctx context.Context, raddr ma.Multiaddr, p peer.ID) (tpt.CapableConn, error) {
+	macon, err := t.maDial(ctx, raddr)
+	if err != nil {
+		return nil, err
+	}
+	return t.Upgrader.UpgradeOutbound(ctx, t, macon, p)
+}
+
+func (t *WebsocketTransport) maDial(ctx context.Context, raddr ma.Multiaddr) (manet.Conn, error) {
+	wsurl, err := parseMultiaddr(raddr)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := js.Global().Get("WebSocket").New(wsurl.String())
+	c := NewConn(raw)
+	if err := c.waitForOpen(); err != nil {
+		return nil, err
+	}
+
+	return manet.WrapNetConn(c)
+}
+
+func (t *WebsocketTransport) Listen(a ma.Multiaddr) (tpt.Listener, error) {
+	return nil, fmt.Errorf("websocket transport cannot listen in js/wasm")
+}
@@ -0,0 +1,245 @@
+// +build !js
+
+package websocket
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	tpt "github.com/libp2p/go-libp2p-core/transport"
+	tptu "github.com/libp2p/go-libp2p-transport-upgrader"
+
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr-net"
+
+	ws "github.com/gorilla/websocket"
+)
+
+// Default tuning for WithConnectionReuse when the caller passes a
+// non-positive maxIdle or idleTimeout.
+const (
+	defaultMaxIdleConns    = 8
+	defaultIdleConnTimeout = 90 * time.Second
+)
+
+// WebsocketTransport is the actual go-libp2p transport for WebSockets and
+// Secure WebSockets (wss).
+type WebsocketTransport struct {
+	Upgrader *tptu.Upgrader
+
+	// tlsConf, when set, is used both to terminate TLS on wss listeners and
+	// as the client configuration for wss dials.
+	tlsConf *tls.Config
+
+	// proxy resolves the HTTP CONNECT proxy to use for a given outbound
+	// dial, mirroring http.Transport.Proxy.
+	proxy func(*http.Request) (*url.URL, error)
+
+	// pool, when set, caches idle outbound connections for reuse instead of
+	// dialing fresh for every Dial call to the same destination.
+	pool *connPool
+
+	// keepAliveInterval and keepAliveTimeout configure application-level
+	// ping/pong keep-alive on every Conn this transport produces. Keep-alive
+	// is disabled when keepAliveInterval is zero.
+	keepAliveInterval time.Duration
+	keepAliveTimeout  time.Duration
+
+	// compression configures permessage-deflate. Compression is disabled
+	// unless WithCompression is used.
+	compressionEnabled   bool
+	compressionLevel     int
+	compressionThreshold int
+}
+
+var _ tpt.Transport = (*WebsocketTransport)(nil)
+
+// Option configures a WebsocketTransport.
+type Option func(*WebsocketTransport)
+
+// WithTLSConfig sets the TLS configuration used to terminate wss listeners
+// and to dial wss addresses. Without this option, the transport can still
+// dial/listen on plain ws addresses, but Dial and Listen will fail for wss
+// multiaddrs.
+func WithTLSConfig(conf *tls.Config) Option {
+	return func(t *WebsocketTransport) {
+		t.tlsConf = conf
+	}
+}
+
+// WithProxy sets the function used to resolve an HTTP CONNECT proxy for
+// outbound dials. It defaults to http.ProxyFromEnvironment. If the resolved
+// proxy URL carries userinfo, it is sent as HTTP Basic auth on the CONNECT
+// request (handled by the underlying gorilla/websocket dialer).
+func WithProxy(proxy func(*http.Request) (*url.URL, error)) Option {
+	return func(t *WebsocketTransport) {
+		t.proxy = proxy
+	}
+}
+
+// WithConnectionReuse opts into pooling idle outbound connections keyed by
+// destination multiaddr: repeated Dial calls to the same peer borrow an
+// idle Conn instead of paying for a fresh TCP+WS handshake. maxIdle bounds
+// the number of idle connections kept per destination, and idleTimeout is
+// how long an idle connection is kept before it is closed. A non-positive
+// value for either falls back to a sane default.
+func WithConnectionReuse(maxIdle int, idleTimeout time.Duration) Option {
+	if maxIdle <= 0 {
+		maxIdle = defaultMaxIdleConns
+	}
+	if idleTimeout <= 0 {
+		idleTimeout = defaultIdleConnTimeout
+	}
+	return func(t *WebsocketTransport) {
+		t.pool = newConnPool(maxIdle, idleTimeout)
+	}
+}
+
+// WithKeepAlive enables application-level ping/pong keep-alive on every Conn
+// produced by this transport, pinging every interval and treating the
+// connection as dead if no pong arrives within timeout. See Conn.KeepAlive.
+func WithKeepAlive(interval, timeout time.Duration) Option {
+	return func(t *WebsocketTransport) {
+		t.keepAliveInterval = interval
+		t.keepAliveTimeout = timeout
+	}
+}
+
+// WithCompression enables RFC 7692 permessage-deflate on every Conn this
+// transport produces, compressing at level (see flate.NewWriter) and
+// skipping compression for individual writes smaller than threshold bytes,
+// so small libp2p mplex frames aren't paid the deflate overhead. This is a
+// no-op in js/wasm builds, where the browser negotiates compression itself.
+func WithCompression(level, threshold int) Option {
+	return func(t *WebsocketTransport) {
+		t.compressionEnabled = true
+		t.compressionLevel = level
+		t.compressionThreshold = threshold
+	}
+}
+
+// New creates a new WebsocketTransport, applying any options given.
+func New(u *tptu.Upgrader, opts ...Option) *WebsocketTransport {
+	t := &WebsocketTransport{
+		Upgrader: u,
+		proxy:    http.ProxyFromEnvironment,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+func (t *WebsocketTransport) CanDial(a ma.Multiaddr) bool {
+	return WsFmt.Matches(a) || WssFmt.Matches(a)
+}
+
+func (t *WebsocketTransport) Protocols() []int {
+	return []int{P_WS, P_WSS}
+}
+
+func (t *WebsocketTransport) Proxy() bool {
+	return false
+}
+
+func (t *WebsocketTransport) Resolve(ctx context.Context, maddr ma.Multiaddr) ([]ma.Multiaddr, error) {
+	return []ma.Multiaddr{maddr}, nil
+}
+
+func (t *WebsocketTransport) Dial(ctx context.Context, raddr ma.Multiaddr, p peer.ID) (tpt.CapableConn, error) {
+	macon, err := t.maDial(ctx, raddr)
+	if err != nil {
+		return nil, err
+	}
+	return t.Upgrader.UpgradeOutbound(ctx, t, macon, p)
+}
+
+func (t *WebsocketTransport) maDial(ctx context.Context, raddr ma.Multiaddr) (manet.Conn, error) {
+	wsurl, err := parseMultiaddr(raddr)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.pool != nil {
+		if c := t.pool.Take(wsurl.String()); c != nil {
+			// A reused Conn's pingLoop already exited (via c.closed) when it
+			// was recycled, and checkout doesn't restart it, so keep-alive
+			// must be re-armed here just like on a fresh dial. Compression
+			// state lives on the underlying gorilla/websocket Conn itself
+			// and survives recycling, but EnableCompression also needs to
+			// reapply this transport's threshold if it changed.
+			if t.keepAliveInterval > 0 {
+				c.KeepAlive(t.keepAliveInterval, t.keepAliveTimeout)
+			}
+			if t.compressionEnabled {
+				c.EnableCompression(t.compressionLevel, t.compressionThreshold)
+			}
+			return manet.WrapNetConn(c)
+		}
+	}
+
+	dialer := &ws.Dialer{
+		HandshakeTimeout:  ws.DefaultDialer.HandshakeTimeout,
+		Proxy:             t.proxy,
+		EnableCompression: t.compressionEnabled,
+	}
+	if wsurl.Scheme == "wss" {
+		if t.tlsConf == nil {
+			return nil, fmt.Errorf("cannot dial %s: transport has no TLS configuration", raddr)
+		}
+		dialer.TLSClientConfig = t.tlsConf
+	}
+
+	wscon, _, err := dialer.DialContext(ctx, wsurl.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	c := NewConn(wscon)
+	c.secure = wsurl.Scheme == "wss"
+	if t.pool != nil {
+		c.recycler = t.pool
+		c.dest = wsurl.String()
+	}
+	if t.keepAliveInterval > 0 {
+		c.KeepAlive(t.keepAliveInterval, t.keepAliveTimeout)
+	}
+	if t.compressionEnabled {
+		c.EnableCompression(t.compressionLevel, t.compressionThreshold)
+	}
+
+	mnc, err := manet.WrapNetConn(c)
+	if err != nil {
+		wscon.Close()
+		return nil, err
+	}
+	return mnc, nil
+}
+
+func (t *WebsocketTransport) Listen(a ma.Multiaddr) (tpt.Listener, error) {
+	var tlsConf *tls.Config
+	if WssFmt.Matches(a) {
+		if t.tlsConf == nil {
+			return nil, fmt.Errorf("cannot listen on %s: transport has no TLS configuration", a)
+		}
+		tlsConf = t.tlsConf
+	}
+
+	l, err := newListener(a, listenerConfig{
+		tlsConf:              tlsConf,
+		keepAliveInterval:    t.keepAliveInterval,
+		keepAliveTimeout:     t.keepAliveTimeout,
+		compressionEnabled:   t.compressionEnabled,
+		compressionLevel:     t.compressionLevel,
+		compressionThreshold: t.compressionThreshold,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t.Upgrader.UpgradeListener(t, l), nil
+}
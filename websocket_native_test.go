@@ -0,0 +1,111 @@
+// +build !js
+
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// startFakeConnectProxy starts a minimal HTTP CONNECT proxy that tunnels raw
+// bytes between the client and whatever host:port it's asked to CONNECT to,
+// and returns its address.
+func startFakeConnectProxy(t *testing.T) (addr string, closeFn func()) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start fake proxy: %s", err)
+	}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveConnect(conn)
+		}
+	}()
+
+	return ln.Addr().String(), func() { ln.Close() }
+}
+
+func serveConnect(client net.Conn) {
+	defer client.Close()
+
+	br := bufio.NewReader(client)
+	req, err := http.ReadRequest(br)
+	if err != nil || req.Method != http.MethodConnect {
+		return
+	}
+
+	upstream, err := net.Dial("tcp", req.Host)
+	if err != nil {
+		fmt.Fprintf(client, "HTTP/1.1 502 Bad Gateway\r\n\r\n")
+		return
+	}
+	defer upstream.Close()
+
+	fmt.Fprintf(client, "HTTP/1.1 200 Connection Established\r\n\r\n")
+
+	done := make(chan struct{}, 2)
+	go func() { io.Copy(upstream, br); done <- struct{}{} }()
+	go func() { io.Copy(client, upstream); done <- struct{}{} }()
+	<-done
+}
+
+// TestMaDialThroughProxy verifies that WithProxy routes an outbound dial
+// through an HTTP CONNECT proxy sitting in front of a plain ws listener.
+func TestMaDialThroughProxy(t *testing.T) {
+	l, err := newListener(ma.StringCast("/ip4/127.0.0.1/tcp/0/ws"), listenerConfig{})
+	if err != nil {
+		t.Fatalf("failed to start listener: %s", err)
+	}
+	defer l.Close()
+
+	proxyAddr, closeProxy := startFakeConnectProxy(t)
+	defer closeProxy()
+
+	proxyURL := &url.URL{Scheme: "http", Host: proxyAddr}
+	transport := New(nil, WithProxy(func(*http.Request) (*url.URL, error) {
+		return proxyURL, nil
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dialed, err := transport.maDial(ctx, l.Multiaddr())
+	if err != nil {
+		t.Fatalf("dial through proxy failed: %s", err)
+	}
+	defer dialed.Close()
+
+	accepted, err := l.Accept()
+	if err != nil {
+		t.Fatalf("accept failed: %s", err)
+	}
+	defer accepted.Close()
+
+	msg := []byte("hello over proxy")
+	if _, err := dialed.Write(msg); err != nil {
+		t.Fatalf("write failed: %s", err)
+	}
+
+	buf := make([]byte, len(msg))
+	if _, err := io.ReadFull(accepted, buf); err != nil {
+		t.Fatalf("read failed: %s", err)
+	}
+	if string(buf) != string(msg) {
+		t.Fatalf("expected %q, got %q", msg, buf)
+	}
+}